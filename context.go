@@ -0,0 +1,125 @@
+package glog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// contextField pairs a context.Context key with the label it should be
+// logged under.
+type contextField struct {
+	key   interface{}
+	label string
+}
+
+// RegisterContextKey declares that the value stored under key in a
+// context.Context should be extracted and logged as label whenever one
+// of the *Context logging methods is used. This lets callers propagate
+// trace IDs, request IDs, tenant IDs, and the like without threading a
+// logger through every function signature.
+func (l *loggingT) RegisterContextKey(key interface{}, label string) {
+	l.ctxMu.Lock()
+	defer l.ctxMu.Unlock()
+	l.ctxFields = append(l.ctxFields, contextField{key: key, label: label})
+}
+
+// extractContext returns the registered fields present in ctx as
+// alternating label/value pairs, in registration order.
+func (l *loggingT) extractContext(ctx context.Context) []interface{} {
+	l.ctxMu.Lock()
+	fields := l.ctxFields
+	l.ctxMu.Unlock()
+	if len(fields) == 0 {
+		return nil
+	}
+	kv := make([]interface{}, 0, len(fields)*2)
+	for _, f := range fields {
+		if v := ctx.Value(f.key); v != nil {
+			kv = append(kv, f.label, v)
+		}
+	}
+	return kv
+}
+
+// logContext renders msg together with ctx's registered fields. An
+// Encoder that implements ContextPlacer controls where those fields go
+// (TextEncoder puts them between the header and msg); any other Encoder,
+// including a custom one installed via SetEncoder, gets them through its
+// regular Encode as trailing key/value pairs, the same as the Xw methods.
+func (l *loggingT) logContext(s Severity, depth int, ctx context.Context, msg string) {
+	kv := l.extractContext(ctx)
+	file, line := callerInfo(depth)
+	buf := l.getBuffer()
+	enc := l.loadEncoder()
+	if cp, ok := enc.(ContextPlacer); ok {
+		cp.EncodeContext(buf, s, file, line, kv, msg)
+	} else {
+		enc.Encode(buf, s, file, line, msg, kv)
+	}
+	l.output(s, buf, file, line)
+}
+
+func (l *loggingT) DebugContext(ctx context.Context, args ...interface{}) {
+	if DebugLog >= l.logLevel {
+		l.logContext(DebugLog, 0, ctx, fmt.Sprint(args...))
+	}
+}
+
+func (l *loggingT) InfoContext(ctx context.Context, args ...interface{}) {
+	if InfoLog >= l.logLevel {
+		l.logContext(InfoLog, 0, ctx, fmt.Sprint(args...))
+	}
+}
+
+func (l *loggingT) WarningContext(ctx context.Context, args ...interface{}) {
+	if WarningLog >= l.logLevel {
+		l.logContext(WarningLog, 0, ctx, fmt.Sprint(args...))
+	}
+}
+
+func (l *loggingT) ErrorContext(ctx context.Context, args ...interface{}) {
+	if ErrorLog >= l.logLevel {
+		l.logContext(ErrorLog, 0, ctx, fmt.Sprint(args...))
+	}
+}
+
+func (l *loggingT) FatalContext(ctx context.Context, args ...interface{}) {
+	if FatalLog >= l.logLevel {
+		l.logContext(FatalLog, 0, ctx, fmt.Sprint(args...))
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
+		l.exit(errors.New(""))
+	}
+}
+
+func (l *loggingT) DebugfContext(ctx context.Context, format string, args ...interface{}) {
+	if DebugLog >= l.logLevel {
+		l.logContext(DebugLog, 0, ctx, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *loggingT) InfofContext(ctx context.Context, format string, args ...interface{}) {
+	if InfoLog >= l.logLevel {
+		l.logContext(InfoLog, 0, ctx, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *loggingT) WarningfContext(ctx context.Context, format string, args ...interface{}) {
+	if WarningLog >= l.logLevel {
+		l.logContext(WarningLog, 0, ctx, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *loggingT) ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	if ErrorLog >= l.logLevel {
+		l.logContext(ErrorLog, 0, ctx, fmt.Sprintf(format, args...))
+	}
+}
+
+func (l *loggingT) FatalfContext(ctx context.Context, format string, args ...interface{}) {
+	if FatalLog >= l.logLevel {
+		l.logContext(FatalLog, 0, ctx, fmt.Sprintf(format, args...))
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
+		l.exit(errors.New(""))
+	}
+}
@@ -0,0 +1,35 @@
+package glog
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+type ctxKeyType struct{}
+
+// TestContextFieldExtraction exercises RegisterContextKey/InfoContext
+// end to end: a registered key present in ctx must show up in the
+// rendered line, and one absent from ctx must be omitted rather than
+// printed as e.g. "<nil>".
+func TestContextFieldExtraction(t *testing.T) {
+	l := &loggingT{}
+	var buf strings.Builder
+	l.SetOutput(&buf)
+	l.RegisterContextKey(ctxKeyType{}, "req_id")
+
+	ctx := context.WithValue(context.Background(), ctxKeyType{}, "abc123")
+	l.InfoContext(ctx, "handling request")
+	l.InfoContext(context.Background(), "no context value")
+
+	lines := strings.Split(strings.TrimRight(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d lines, want 2: %q", len(lines), buf.String())
+	}
+	if !strings.Contains(lines[0], "req_id=abc123") {
+		t.Errorf("line with ctx value = %q, want it to contain req_id=abc123", lines[0])
+	}
+	if strings.Contains(lines[1], "req_id") {
+		t.Errorf("line without ctx value = %q, want no req_id field", lines[1])
+	}
+}
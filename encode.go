@@ -0,0 +1,250 @@
+package glog
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// Encoder renders one log entry — header, message, and any key/value
+// pairs — into buf. SetEncoder selects the Encoder used by the Xw
+// methods (Debugw, Infow, Warningw, Errorw, Fatalw) and the *Context
+// methods.
+type Encoder interface {
+	Encode(buf *buffer, s Severity, file string, line int, msg string, kv []interface{})
+}
+
+// encoderBox wraps an Encoder so l.enc (an atomic.Value) always stores
+// the same concrete type regardless of which Encoder is installed;
+// atomic.Value panics if consecutive Store calls disagree on type, which
+// storing e directly would trigger the first time SetEncoder switched
+// from TextEncoder to JSONEncoder or back.
+type encoderBox struct{ enc Encoder }
+
+// SetEncoder selects the Encoder used by the Xw and *Context methods. The
+// default is TextEncoder.
+func (l *loggingT) SetEncoder(e Encoder) {
+	l.enc.Store(encoderBox{e})
+}
+
+// loadEncoder returns the Encoder installed by SetEncoder, or TextEncoder
+// if none has been set yet.
+func (l *loggingT) loadEncoder() Encoder {
+	if box, ok := l.enc.Load().(encoderBox); ok {
+		return box.enc
+	}
+	return TextEncoder{}
+}
+
+// ContextPlacer is an optional interface an Encoder can implement to
+// control where context-derived fields (see RegisterContextKey) land
+// relative to the message, instead of the default of appending them as
+// trailing key/value pairs via Encode. TextEncoder implements it to put
+// them between the header and the message; JSONEncoder doesn't need to,
+// since Encode's trailing kv already render as top-level JSON fields.
+type ContextPlacer interface {
+	EncodeContext(buf *buffer, s Severity, file string, line int, ctxKV []interface{}, msg string)
+}
+
+// TextEncoder is the default Encoder: the same header formatHeader
+// produces for the plain print path, followed by the message and any
+// key/value pairs rendered as "key=value", space separated.
+type TextEncoder struct{}
+
+func (TextEncoder) Encode(buf *buffer, s Severity, file string, line int, msg string, kv []interface{}) {
+	if line < 0 {
+		line = 0
+	}
+	if s > FatalLog {
+		s = FatalLog
+	}
+	writeTextHeader(buf, s, file, line)
+	buf.WriteString(msg)
+	writeTextKV(buf, kv)
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+}
+
+// EncodeContext puts ctxKV right after the header and before msg, unlike
+// Encode which appends key/value pairs after the message.
+func (TextEncoder) EncodeContext(buf *buffer, s Severity, file string, line int, ctxKV []interface{}, msg string) {
+	if line < 0 {
+		line = 0
+	}
+	if s > FatalLog {
+		s = FatalLog
+	}
+	writeTextHeader(buf, s, file, line)
+	writeTextKV(buf, ctxKV)
+	if len(ctxKV) > 0 {
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(msg)
+	if buf.Len() == 0 || buf.Bytes()[buf.Len()-1] != '\n' {
+		buf.WriteByte('\n')
+	}
+}
+
+// writeTextKV appends alternating key/value pairs to buf as " key=value".
+func writeTextKV(buf *buffer, kv []interface{}) {
+	for i := 0; i+1 < len(kv); i += 2 {
+		buf.WriteByte(' ')
+		fmt.Fprintf(buf, "%v", kv[i])
+		buf.WriteByte('=')
+		switch v := kv[i+1].(type) {
+		case string:
+			buf.WriteString(v)
+		case error:
+			buf.WriteString(v.Error())
+		default:
+			fmt.Fprintf(buf, "%v", v)
+		}
+	}
+}
+
+// JSONEncoder renders an entry as a single-line JSON object:
+// {"ts":...,"level":"I","file":"foo.go:42","msg":"...","k":v,...}. It
+// hand-writes the common value types (string, int, float, bool, error)
+// straight into the pooled buffer instead of going through
+// encoding/json's reflection, keeping the hot path the package already
+// optimizes for in formatHeader; anything else falls back to
+// fmt.Sprintf("%v", ...).
+type JSONEncoder struct{}
+
+func (JSONEncoder) Encode(buf *buffer, s Severity, file string, line int, msg string, kv []interface{}) {
+	if s > FatalLog {
+		s = FatalLog
+	}
+	now := timeNow()
+	buf.WriteString(`{"ts":"`)
+	buf.WriteString(now.Format("2006-01-02T15:04:05.000000Z07:00"))
+	buf.WriteString(`","level":"`)
+	buf.WriteByte(severityChar[s])
+	buf.WriteString(`","file":"`)
+	writeJSONString(buf, file)
+	buf.WriteByte(':')
+	fmt.Fprintf(buf, "%d", line)
+	buf.WriteString(`","msg":"`)
+	writeJSONString(buf, msg)
+	buf.WriteByte('"')
+	for i := 0; i+1 < len(kv); i += 2 {
+		buf.WriteString(`,"`)
+		writeJSONString(buf, fmt.Sprintf("%v", kv[i]))
+		buf.WriteString(`":`)
+		writeJSONValue(buf, kv[i+1])
+	}
+	buf.WriteString("}\n")
+}
+
+// jsonEscape maps the control characters encoding/json gives a short
+// escape to; everything else below 0x20 falls back to \u00XX in
+// writeJSONString.
+var jsonEscape = map[rune]string{
+	'"':  `\"`,
+	'\\': `\\`,
+	'\n': `\n`,
+	'\r': `\r`,
+	'\t': `\t`,
+	'\b': `\b`,
+	'\f': `\f`,
+}
+
+// writeJSONString writes s into buf as a double-quoted JSON string,
+// escaping the quote/backslash characters and the full 0x00-0x1F control
+// range the way encoding/json does; an unescaped control character (e.g.
+// a literal tab in an error message) would otherwise produce invalid
+// JSON that json.Unmarshal rejects.
+func writeJSONString(buf *buffer, s string) {
+	for _, r := range s {
+		if esc, ok := jsonEscape[r]; ok {
+			buf.WriteString(esc)
+			continue
+		}
+		if r < 0x20 {
+			fmt.Fprintf(buf, `\u%04x`, r)
+			continue
+		}
+		buf.WriteRune(r)
+	}
+}
+
+func writeJSONValue(buf *buffer, v interface{}) {
+	switch x := v.(type) {
+	case string:
+		buf.WriteByte('"')
+		writeJSONString(buf, x)
+		buf.WriteByte('"')
+	case bool:
+		if x {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case int, int32, int64, uint, uint32, uint64:
+		fmt.Fprintf(buf, "%d", x)
+	case float32, float64:
+		fmt.Fprintf(buf, "%g", x)
+	case error:
+		buf.WriteByte('"')
+		writeJSONString(buf, x.Error())
+		buf.WriteByte('"')
+	default:
+		buf.WriteByte('"')
+		writeJSONString(buf, fmt.Sprintf("%v", x))
+		buf.WriteByte('"')
+	}
+}
+
+// callerInfo resolves the short filename and line number of the log call
+// site, the same way header does for the non-structured print path.
+func callerInfo(depth int) (file string, line int) {
+	_, file, line, ok := runtime.Caller(3 + depth)
+	if !ok {
+		return "???", 1
+	}
+	if slash := strings.LastIndex(file, "/"); slash >= 0 {
+		file = file[slash+1:]
+	}
+	return file, line
+}
+
+func (l *loggingT) outputw(s Severity, depth int, msg string, kv []interface{}) {
+	file, line := callerInfo(depth)
+	buf := l.getBuffer()
+	l.loadEncoder().Encode(buf, s, file, line, msg, kv)
+	l.output(s, buf, file, line)
+}
+
+func (l *loggingT) Debugw(msg string, kv ...interface{}) {
+	if DebugLog >= l.logLevel {
+		l.outputw(DebugLog, 0, msg, kv)
+	}
+}
+
+func (l *loggingT) Infow(msg string, kv ...interface{}) {
+	if InfoLog >= l.logLevel {
+		l.outputw(InfoLog, 0, msg, kv)
+	}
+}
+
+func (l *loggingT) Warningw(msg string, kv ...interface{}) {
+	if WarningLog >= l.logLevel {
+		l.outputw(WarningLog, 0, msg, kv)
+	}
+}
+
+func (l *loggingT) Errorw(msg string, kv ...interface{}) {
+	if ErrorLog >= l.logLevel {
+		l.outputw(ErrorLog, 0, msg, kv)
+	}
+}
+
+func (l *loggingT) Fatalw(msg string, kv ...interface{}) {
+	if FatalLog >= l.logLevel {
+		l.outputw(FatalLog, 0, msg, kv)
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
+		l.exit(errors.New(""))
+	}
+}
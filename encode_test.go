@@ -0,0 +1,57 @@
+package glog
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"testing"
+)
+
+// TestJSONEncoderRoundTrip guards against writeJSONString emitting raw
+// control characters: a message or kv value containing a tab, newline, or
+// other 0x00-0x1F byte used to produce output that json.Unmarshal
+// rejected outright, defeating the point of a "machine-parseable" encoder.
+func TestJSONEncoderRoundTrip(t *testing.T) {
+	buf := &buffer{}
+	JSONEncoder{}.Encode(buf, InfoLog, "f.go", 1, "line one\tline two\nquote\"here", []interface{}{"err", "boom\r\n"})
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("json.Unmarshal(%q) failed: %v", buf.Bytes(), err)
+	}
+	if decoded["msg"] != "line one\tline two\nquote\"here" {
+		t.Errorf("msg = %q, want round-tripped original", decoded["msg"])
+	}
+	if decoded["err"] != "boom\r\n" {
+		t.Errorf("err = %q, want round-tripped original", decoded["err"])
+	}
+}
+
+// TestSetEncoderNoRace exercises SetEncoder running concurrently with
+// Infow. Before enc moved to an atomic.Value, SetEncoder mutated l.enc
+// under l.mu while outputw/logContext read it unsynchronized; run with
+// -race to catch a regression.
+func TestSetEncoderNoRace(t *testing.T) {
+	l := &loggingT{}
+	l.SetOutput(io.Discard)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			l.Infow("log", "i", i)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 200; i++ {
+			if i%2 == 0 {
+				l.SetEncoder(JSONEncoder{})
+			} else {
+				l.SetEncoder(TextEncoder{})
+			}
+		}
+	}()
+	wg.Wait()
+}
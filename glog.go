@@ -1,7 +1,6 @@
 package glog
 
 import (
-	"bufio"
 	"bytes"
 	"errors"
 	"fmt"
@@ -10,6 +9,7 @@ import (
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -43,7 +43,17 @@ type flushSyncWriter interface {
 	io.Writer
 }
 
+// Flush flushes and syncs the log file. In async mode this drains the
+// channel synchronously: a flush sentinel is enqueued behind any pending
+// log entries, and Flush blocks until the writer goroutine has processed
+// everything ahead of it.
 func (l *loggingT) Flush() {
+	if atomic.LoadInt32(&l.asyncEnabled) == 1 {
+		done := make(chan struct{})
+		l.asyncCh <- &asyncMsg{flushDone: done}
+		<-done
+		return
+	}
 	l.lockAndFlushAll()
 }
 
@@ -66,29 +76,238 @@ func (l *loggingT) flushAll() {
 
 func (l *loggingT) flushDaemon(flushInterval int) {
 	for range time.NewTicker(time.Duration(flushInterval) * time.Second).C {
-		l.lockAndFlushAll()
+		l.Flush()
 	}
 }
 
 type loggingT struct {
-	logPath     string
-	logLevel    Severity
-	fileMaxSize uint64 //flushInterval int
-	mu          sync.Mutex
-	file        flushSyncWriter // syncBuffer
+	logPath         string
+	logLevel        Severity
+	fileMaxSize     uint64 //flushInterval int
+	mu              sync.Mutex
+	file            flushSyncWriter // the primary sink: a syncBuffer, or the writer given to SetOutput
+	sinks           []*sink
+	stderrThreshold Severity
+
+	asyncEnabled int32 // atomic; 1 once Async has been called
+	asyncCh      chan *asyncMsg
+	dropPolicy   int32  // atomic DropPolicy
+	dropped      uint64 // atomic count of entries lost to the drop policy
+
+	enc atomic.Value // stores encoderBox wrapping the Encoder set by SetEncoder; read lock-free by outputw/logContext, same as vmodule/vcache below
+
+	rotate RotateConfig
+
+	verbosity int32        // atomic; default V threshold set by SetVerbosity
+	vmodule   atomic.Value // stores []vmoduleRule, set by SetVModule
+	vcache    atomic.Value // stores *sync.Map of caller PC -> effective V threshold
+
+	ctxMu     sync.Mutex
+	ctxFields []contextField // keys auto-extracted from a context.Context by the *Context methods
 }
 
-func NewLogger(logPath, fileMaxSize string, logLevel Severity, flushInterval int) *loggingT {
+// noStderr is the default stderrThreshold: one level above FatalLog, so no
+// real severity ever meets it and the stderr copy stays off until a caller
+// opts in via StderrThreshold.
+const noStderr Severity = FatalLog + 1
+
+// NewLogger creates a logger that writes to logPath, rotating once the
+// file reaches fileMaxSize (a size string like "100M" or "1G"). rotate is
+// optional; pass a RotateConfig to enable daily rotation, backup
+// cleanup, or the symlink-to-current scheme on top of the size check.
+func NewLogger(logPath, fileMaxSize string, logLevel Severity, flushInterval int, rotate ...RotateConfig) *loggingT {
 	n := unitConv(fileMaxSize)
 	logger := &loggingT{
-		logPath:     logPath,
-		logLevel:    logLevel,
-		fileMaxSize: n,
+		logPath:         logPath,
+		logLevel:        logLevel,
+		fileMaxSize:     n,
+		stderrThreshold: noStderr,
+	}
+	if len(rotate) > 0 {
+		logger.rotate = rotate[0]
 	}
 	go logger.flushDaemon(flushInterval)
 	return logger
 }
 
+// sink is an additional destination for log output, copied to alongside the
+// primary file whenever an event's severity is at least min.
+type sink struct {
+	name string
+	w    flushSyncWriter
+	min  Severity
+}
+
+// nopFlushSyncWriter adapts a plain io.Writer to flushSyncWriter so it can
+// be used as a sink or as the primary output; Flush and Sync are no-ops.
+type nopFlushSyncWriter struct {
+	io.Writer
+}
+
+func (nopFlushSyncWriter) Flush() error { return nil }
+func (nopFlushSyncWriter) Sync() error  { return nil }
+
+func wrapWriter(w io.Writer) flushSyncWriter {
+	if fsw, ok := w.(flushSyncWriter); ok {
+		return fsw
+	}
+	return nopFlushSyncWriter{w}
+}
+
+// SetOutput replaces the logger's primary sink with w, bypassing the
+// file-backed sink and its rotation logic entirely. This is mainly useful
+// in tests, which can pass a *bytes.Buffer to capture output.
+func (l *loggingT) SetOutput(w io.Writer) {
+	l.configure(func() { l.file = wrapWriter(w) })
+}
+
+// AddSink registers an additional writer that receives a copy of every log
+// event whose severity is at least minSeverity, alongside the primary
+// sink. name is for the caller's own bookkeeping; it does not appear in
+// the log output.
+func (l *loggingT) AddSink(name string, w io.Writer, minSeverity Severity) {
+	sk := &sink{name: name, w: wrapWriter(w), min: minSeverity}
+	l.configure(func() { l.sinks = append(l.sinks, sk) })
+}
+
+// StderrThreshold sets the minimum severity copied to stderr in addition
+// to the file and any configured sinks, mirroring klog's -stderrthreshold
+// flag. Pass a severity above FatalLog to disable the stderr copy again.
+func (l *loggingT) StderrThreshold(s Severity) {
+	l.configure(func() { l.stderrThreshold = s })
+}
+
+// configure applies a mutation of l.file/l.sinks/l.stderrThreshold. Once
+// Async is enabled those fields belong exclusively to the writer
+// goroutine (see writeToSinks), so the mutation is routed through the
+// same channel as log entries instead of being applied directly; this
+// keeps it ordered with, and free of data races against, the writer's
+// unsynchronized reads. Outside async mode it runs under l.mu as before.
+func (l *loggingT) configure(fn func()) {
+	if atomic.LoadInt32(&l.asyncEnabled) == 1 {
+		l.asyncCh <- &asyncMsg{configFn: fn}
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	fn()
+}
+
+// DropPolicy controls what Async does with a log entry when the async
+// queue is full.
+type DropPolicy int32
+
+const (
+	// Block makes the caller wait for room in the queue, same as the
+	// synchronous path would wait for l.mu.
+	Block DropPolicy = iota
+	// DropOldest discards the queue's oldest pending entry to make room.
+	// A flush sentinel, configure() message or fatal entry at the head is
+	// put back at the tail instead, and the caller waits for room.
+	DropOldest
+	// DropNewest discards the entry that was about to be enqueued.
+	DropNewest
+)
+
+// asyncMsg is a formatted log entry bound for the writer goroutine; or,
+// when flushDone is non-nil, a flush sentinel: the writer closes
+// flushDone once every entry ahead of it has been written; or, when
+// configFn is non-nil, a configure() control message.
+type asyncMsg struct {
+	severity  Severity
+	buf       *buffer
+	file      string
+	line      int
+	flushDone chan struct{}
+	configFn  func() // non-nil only for a configure() control message
+}
+
+// Async switches the logger into asynchronous mode: output() enqueues
+// formatted entries onto a channel of size bufSize instead of taking l.mu
+// on every call, and a dedicated writer goroutine owns l.file from then
+// on. Calling Async more than once is a no-op.
+func (l *loggingT) Async(bufSize int) {
+	l.mu.Lock()
+	if l.asyncCh != nil {
+		l.mu.Unlock()
+		return
+	}
+	l.asyncCh = make(chan *asyncMsg, bufSize)
+	l.mu.Unlock()
+	atomic.StoreInt32(&l.asyncEnabled, 1)
+	go l.asyncWriter()
+}
+
+// SetDropPolicy sets the policy applied when the async queue is full. It
+// only has an effect once Async has been called.
+func (l *loggingT) SetDropPolicy(p DropPolicy) {
+	atomic.StoreInt32(&l.dropPolicy, int32(p))
+}
+
+// Dropped returns the number of entries discarded so far under
+// DropOldest or DropNewest.
+func (l *loggingT) Dropped() uint64 {
+	return atomic.LoadUint64(&l.dropped)
+}
+
+// enqueue hands a formatted entry to the async writer goroutine, applying
+// the configured DropPolicy if the queue is currently full.
+func (l *loggingT) enqueue(s Severity, buf *buffer, file string, line int) {
+	msg := &asyncMsg{severity: s, buf: buf, file: file, line: line}
+	if s == FatalLog {
+		// Fatal entries must never be subject to the drop policy: the
+		// process exits right after Flush() drains the queue, and a
+		// discarded fatal message would exit silently with no record of
+		// why.
+		l.asyncCh <- msg
+		return
+	}
+	select {
+	case l.asyncCh <- msg:
+		return
+	default:
+	}
+	switch DropPolicy(atomic.LoadInt32(&l.dropPolicy)) {
+	case DropNewest:
+		atomic.AddUint64(&l.dropped, 1)
+		l.putBuffer(buf)
+	case DropOldest:
+		select {
+		case old := <-l.asyncCh:
+			if old.buf == nil || old.severity == FatalLog {
+				// Never discard a flush sentinel, a configure()
+				// message or a fatal entry; requeue it instead.
+				l.asyncCh <- old
+			} else {
+				l.putBuffer(old.buf)
+				atomic.AddUint64(&l.dropped, 1)
+			}
+		default:
+		}
+		l.asyncCh <- msg
+	default: // Block
+		l.asyncCh <- msg
+	}
+}
+
+// asyncWriter is the sole writer of l.file once Async is enabled: it owns
+// rotation (via syncBuffer.Write) and performs the periodic flush that
+// flushDaemon's ticker requests through a flush sentinel.
+func (l *loggingT) asyncWriter() {
+	for msg := range l.asyncCh {
+		switch {
+		case msg.flushDone != nil:
+			l.flushAll()
+			close(msg.flushDone)
+		case msg.configFn != nil:
+			msg.configFn()
+		default:
+			l.writeToSinks(msg.severity, msg.buf.Bytes())
+			l.putBuffer(msg.buf)
+		}
+	}
+}
+
 func stringToInt(s string) (n uint64, err error) {
 	for _, ch := range s {
 		ch -= '0'
@@ -143,9 +362,23 @@ func (l *loggingT) exit(err error) {
 	os.Exit(2)
 }
 
-func (l *loggingT) output(buf *buffer, file string, line int) {
-	data := buf.Bytes()
+func (l *loggingT) output(s Severity, buf *buffer, file string, line int) {
+	if atomic.LoadInt32(&l.asyncEnabled) == 1 {
+		l.enqueue(s, buf, file, line)
+		return
+	}
 	l.mu.Lock()
+	l.writeToSinks(s, buf.Bytes())
+	l.mu.Unlock()
+	l.putBuffer(buf)
+}
+
+// writeToSinks writes data to the primary file (creating it lazily on
+// first use), to any registered sinks whose threshold permits it, and to
+// stderr if s meets the configured threshold. Callers own whatever
+// synchronization l.file and l.sinks require: the sync path holds l.mu,
+// the async writer goroutine is their sole owner so it needs none.
+func (l *loggingT) writeToSinks(s Severity, data []byte) {
 	if l.file == nil {
 		sb := &syncBuffer{
 			logger: l,
@@ -156,58 +389,14 @@ func (l *loggingT) output(buf *buffer, file string, line int) {
 		}
 	}
 	l.file.Write(data)
-	l.mu.Unlock()
-	l.putBuffer(buf)
-}
-
-type syncBuffer struct {
-	logger *loggingT
-	*bufio.Writer
-	file   *os.File
-	num    int32
-	nbytes uint64 // The number of bytes written to this file
-}
-
-func (sb *syncBuffer) rotateFile(now time.Time) error {
-	sb.Flush()
-	sb.file.Close()
-	filePath := fmt.Sprintf("%s.%d%d%d.%d%d%d", sb.logger.logPath, now.Year(),
-		now.Month(), now.Day(), now.Hour(), now.Minute(), now.Day())
-	if err := os.Rename(sb.logger.logPath, filePath); err != nil {
-		return err
-	}
-	return sb.create()
-
-}
-
-func (sb *syncBuffer) create() (err error) {
-	sb.file, err = os.Create(sb.logger.logPath)
-	if err != nil {
-		return err
-	}
-	//atomic.StoreUint64(&sb.nbytes, 0)
-	sb.nbytes = 0
-	sb.logger.file = sb
-	sb.Writer = bufio.NewWriterSize(sb.file, bufferSize)
-	return nil
-}
-
-func (sb *syncBuffer) Sync() error {
-	return sb.file.Sync()
-}
-
-func (sb *syncBuffer) Write(p []byte) (n int, err error) {
-	if sb.nbytes+uint64(len(p)) >= sb.logger.fileMaxSize {
-		if err := sb.rotateFile(time.Now()); err != nil {
-			sb.logger.exit(err)
+	for _, sk := range l.sinks {
+		if s >= sk.min {
+			sk.w.Write(data)
 		}
 	}
-	n, err = sb.Writer.Write(p)
-	sb.nbytes += uint64(n)
-	if err != nil {
-		sb.logger.exit(err)
+	if s >= l.stderrThreshold {
+		os.Stderr.Write(data)
 	}
-	return
 }
 
 const bufferSize = 256 * 1024
@@ -229,7 +418,6 @@ func (l *loggingT) putBuffer(buf *buffer) {
 }
 
 func (l *loggingT) formatHeader(s Severity, file string, line int) *buffer {
-	now := timeNow()
 	if line < 0 {
 		line = 0 // not a real line number, but acceptable to someDigits
 	}
@@ -237,6 +425,14 @@ func (l *loggingT) formatHeader(s Severity, file string, line int) *buffer {
 		s = FatalLog // for safety.
 	}
 	buf := l.getBuffer()
+	writeTextHeader(buf, s, file, line)
+	return buf
+}
+
+// writeTextHeader writes the "Lyyyy-mm-dd hh:mm:ss.uuuuuu file:line] "
+// header into buf. Shared by formatHeader and TextEncoder.
+func writeTextHeader(buf *buffer, s Severity, file string, line int) {
+	now := timeNow()
 
 	// Avoid Fprintf, for speed. The format is so simple that we can do it quickly by hand.
 	// It's worth about 3X. Fprintf is hard.
@@ -265,7 +461,6 @@ func (l *loggingT) formatHeader(s Severity, file string, line int) *buffer {
 	buf.tmp[n+1] = ']'
 	buf.tmp[n+2] = ' '
 	buf.Write(buf.tmp[:n+3])
-	return buf
 }
 
 const digits = "0123456789"
@@ -310,7 +505,7 @@ func (buf *buffer) someDigits(i, d int) int {
 func (l *loggingT) println(s Severity, args ...interface{}) {
 	buf, file, line := l.header(s, 0)
 	fmt.Fprintln(buf, args...)
-	l.output(buf, file, line)
+	l.output(s, buf, file, line)
 }
 
 func (l *loggingT) print(s Severity, args ...interface{}) {
@@ -323,7 +518,13 @@ func (l *loggingT) printDepth(s Severity, depth int, args ...interface{}) {
 	if buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
 	}
-	l.output(buf, file, line)
+	l.output(s, buf, file, line)
+}
+
+func (l *loggingT) printlnDepth(s Severity, depth int, args ...interface{}) {
+	buf, file, line := l.header(s, depth)
+	fmt.Fprintln(buf, args...)
+	l.output(s, buf, file, line)
 }
 
 func (l *loggingT) printfDepth(s Severity, depth int, format string, args ...interface{}) {
@@ -332,7 +533,7 @@ func (l *loggingT) printfDepth(s Severity, depth int, format string, args ...int
 	if buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
 	}
-	l.output(buf, file, line)
+	l.output(s, buf, file, line)
 }
 
 func (l *loggingT) printf(s Severity, format string, args ...interface{}) {
@@ -341,7 +542,7 @@ func (l *loggingT) printf(s Severity, format string, args ...interface{}) {
 	if buf.Bytes()[buf.Len()-1] != '\n' {
 		buf.WriteByte('\n')
 	}
-	l.output(buf, file, line)
+	l.output(s, buf, file, line)
 }
 
 func (l *loggingT) Debugf(format string, args ...interface{}) {
@@ -371,6 +572,7 @@ func (l *loggingT) Errorf(format string, args ...interface{}) {
 func (l *loggingT) Fatalf(format string, args ...interface{}) {
 	if FatalLog >= l.logLevel {
 		l.printf(FatalLog, format, args...)
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
 		l.exit(errors.New(""))
 	}
 }
@@ -402,6 +604,7 @@ func (l *loggingT) Error(args ...interface{}) {
 func (l *loggingT) Fatal(args ...interface{}) {
 	if FatalLog >= l.logLevel {
 		l.print(FatalLog, args...)
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
 		l.exit(errors.New(""))
 	}
 }
@@ -433,6 +636,7 @@ func (l *loggingT) ErrorDepth(depth int, args ...interface{}) {
 func (l *loggingT) FatalDepth(depth int, args ...interface{}) {
 	if FatalLog >= l.logLevel {
 		l.printDepth(FatalLog, depth, args...)
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
 		l.exit(errors.New(""))
 	}
 }
@@ -464,6 +668,7 @@ func (l *loggingT) ErrorfDepth(depth int, format string, args ...interface{}) {
 func (l *loggingT) FatalfDepth(depth int, format string, args ...interface{}) {
 	if FatalLog >= l.logLevel {
 		l.printfDepth(FatalLog, depth, format, args...)
+		l.Flush() // ensure the fatal entry is written before exiting, even in async mode
 		l.exit(errors.New(""))
 	}
 }
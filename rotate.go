@@ -0,0 +1,177 @@
+package glog
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RotateConfig controls log file rotation beyond the basic size check
+// that NewLogger always applies.
+type RotateConfig struct {
+	// Daily rotates the file when the calendar date of the currently
+	// open file differs from time.Now(), in addition to the size check.
+	Daily bool
+	// MaxBackups caps the number of rotated files kept; the oldest ones
+	// beyond this count are removed. Zero means unlimited.
+	MaxBackups int
+	// MaxAge caps how long a rotated file is kept before it is removed.
+	// Zero means unlimited.
+	MaxAge time.Duration
+	// Symlink, if true, keeps logPath as a symlink to the active file,
+	// which is instead written as logPath.YYYYMMDD-HHMMSS.N. Without
+	// it, the active file is logPath itself, renamed aside on rotation.
+	Symlink bool
+}
+
+const rotateTimeFormat = "20060102-150405"
+
+// rotatedName returns the rotated filename for logPath at t with
+// collision sequence seq.
+func rotatedName(logPath string, t time.Time, seq int) string {
+	return fmt.Sprintf("%s.%s.%d", logPath, t.Format(rotateTimeFormat), seq)
+}
+
+// nextRotatedName returns the first rotatedName for logPath/t that
+// doesn't already exist, to avoid clobbering a file from an earlier
+// rotation in the same second.
+func nextRotatedName(logPath string, t time.Time) string {
+	name := rotatedName(logPath, t, 0)
+	for seq := 1; fileExists(name); seq++ {
+		name = rotatedName(logPath, t, seq)
+	}
+	return name
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+type syncBuffer struct {
+	logger *loggingT
+	*bufio.Writer
+	file       *os.File
+	num        int32
+	nbytes     uint64 // The number of bytes written to this file
+	openedDate string // YYYYMMDD of the calendar day the file was opened, for Daily rotation
+}
+
+// rotateFile closes the active file, moves it aside (or, in Symlink
+// mode, simply leaves it where it already lives) and opens a fresh one.
+func (sb *syncBuffer) rotateFile(now time.Time) error {
+	sb.Flush()
+	sb.file.Close()
+	if !sb.logger.rotate.Symlink {
+		if err := os.Rename(sb.logger.logPath, nextRotatedName(sb.logger.logPath, now)); err != nil {
+			return err
+		}
+	}
+	if err := sb.create(); err != nil {
+		return err
+	}
+	if sb.logger.rotate.MaxBackups > 0 || sb.logger.rotate.MaxAge > 0 {
+		go sb.logger.cleanupOldLogs()
+	}
+	return nil
+}
+
+func (sb *syncBuffer) create() (err error) {
+	now := timeNow()
+	path := sb.logger.logPath
+	if sb.logger.rotate.Symlink {
+		path = nextRotatedName(sb.logger.logPath, now)
+	}
+	sb.file, err = os.Create(path)
+	if err != nil {
+		return err
+	}
+	sb.nbytes = 0
+	sb.openedDate = now.Format("20060102")
+	sb.logger.file = sb
+	sb.Writer = bufio.NewWriterSize(sb.file, bufferSize)
+	if sb.logger.rotate.Symlink {
+		return refreshSymlink(sb.logger.logPath, path)
+	}
+	return nil
+}
+
+// refreshSymlink atomically repoints the symlink at logPath to target,
+// which must live in the same directory.
+func refreshSymlink(logPath, target string) error {
+	tmp := logPath + ".tmp-symlink"
+	os.Remove(tmp) // ignore error; stale tmp link from a crashed prior rotation
+	if err := os.Symlink(filepath.Base(target), tmp); err != nil {
+		return err
+	}
+	return os.Rename(tmp, logPath)
+}
+
+func (sb *syncBuffer) Sync() error {
+	return sb.file.Sync()
+}
+
+func (sb *syncBuffer) Write(p []byte) (n int, err error) {
+	now := timeNow()
+	needRotate := sb.nbytes+uint64(len(p)) >= sb.logger.fileMaxSize
+	if sb.logger.rotate.Daily && now.Format("20060102") != sb.openedDate {
+		needRotate = true
+	}
+	if needRotate {
+		if err := sb.rotateFile(now); err != nil {
+			sb.logger.exit(err)
+		}
+	}
+	n, err = sb.Writer.Write(p)
+	sb.nbytes += uint64(n)
+	if err != nil {
+		sb.logger.exit(err)
+	}
+	return
+}
+
+// cleanupOldLogs removes previously rotated files beyond MaxBackups or
+// older than MaxAge. It runs in its own goroutine after each rotation so
+// it never blocks the writer.
+func (l *loggingT) cleanupOldLogs() {
+	dir := filepath.Dir(l.logPath)
+	prefix := filepath.Base(l.logPath) + "."
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type backup struct {
+		path    string
+		modTime time.Time
+	}
+	var backups []backup
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), prefix) {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		backups = append(backups, backup{path: filepath.Join(dir, e.Name()), modTime: info.ModTime()})
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].modTime.After(backups[j].modTime) // newest first
+	})
+
+	now := timeNow()
+	for i, b := range backups {
+		remove := l.rotate.MaxBackups > 0 && i >= l.rotate.MaxBackups
+		if l.rotate.MaxAge > 0 && now.Sub(b.modTime) > l.rotate.MaxAge {
+			remove = true
+		}
+		if remove {
+			os.Remove(b.path)
+		}
+	}
+}
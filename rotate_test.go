@@ -0,0 +1,108 @@
+package glog
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRotateDaily exercises the Daily trigger in syncBuffer.Write: a
+// write on a later calendar day than the file was opened must rotate the
+// old file aside and start a fresh one at the active log path.
+func TestRotateDaily(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	l := NewLogger(logPath, "1G", InfoLog, 3600, RotateConfig{Daily: true})
+
+	oldNow := timeNow
+	defer func() { timeNow = oldNow }()
+
+	timeNow = func() time.Time { return time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC) }
+	l.Info("day one")
+	l.Flush()
+
+	timeNow = func() time.Time { return time.Date(2026, 1, 2, 12, 0, 0, 0, time.UTC) }
+	l.Info("day two")
+	l.Flush()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("ReadDir: %v", err)
+	}
+	var rotated int
+	for _, e := range entries {
+		if e.Name() != "app.log" {
+			rotated++
+		}
+	}
+	if rotated == 0 {
+		t.Fatal("Daily rotation across a date boundary left no rotated file behind")
+	}
+	if !fileExists(logPath) {
+		t.Fatal("active log path missing after rotation")
+	}
+}
+
+// TestRotateSymlink exercises the Symlink scheme: logPath must end up a
+// symlink pointing at the currently active, timestamped file.
+func TestRotateSymlink(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	l := NewLogger(logPath, "1G", InfoLog, 3600, RotateConfig{Symlink: true})
+
+	l.Info("hello")
+	l.Flush()
+
+	info, err := os.Lstat(logPath)
+	if err != nil {
+		t.Fatalf("Lstat(%q): %v", logPath, err)
+	}
+	if info.Mode()&os.ModeSymlink == 0 {
+		t.Fatalf("%q is not a symlink under Symlink mode", logPath)
+	}
+	target, err := os.Readlink(logPath)
+	if err != nil {
+		t.Fatalf("Readlink: %v", err)
+	}
+	if target == "app.log" {
+		t.Fatalf("symlink target = %q, want a timestamped rotated filename", target)
+	}
+	data, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("ReadFile through symlink: %v", err)
+	}
+	if len(data) == 0 {
+		t.Fatal("no data readable through the symlink")
+	}
+}
+
+// TestCleanupOldLogsMaxAge exercises the MaxAge path of cleanupOldLogs:
+// a rotated backup older than MaxAge must be removed, while one within
+// MaxAge survives.
+func TestCleanupOldLogsMaxAge(t *testing.T) {
+	dir := t.TempDir()
+	logPath := filepath.Join(dir, "app.log")
+	l := &loggingT{logPath: logPath, rotate: RotateConfig{MaxAge: time.Hour}}
+
+	oldPath := logPath + ".old"
+	newPath := logPath + ".new"
+	for _, p := range []string{oldPath, newPath} {
+		if err := os.WriteFile(p, []byte("x"), 0644); err != nil {
+			t.Fatalf("WriteFile(%q): %v", p, err)
+		}
+	}
+	oldTime := time.Now().Add(-2 * time.Hour)
+	if err := os.Chtimes(oldPath, oldTime, oldTime); err != nil {
+		t.Fatalf("Chtimes: %v", err)
+	}
+
+	l.cleanupOldLogs()
+
+	if fileExists(oldPath) {
+		t.Error("backup older than MaxAge was not removed")
+	}
+	if !fileExists(newPath) {
+		t.Error("backup within MaxAge was removed")
+	}
+}
@@ -0,0 +1,25 @@
+package glog
+
+import "testing"
+
+// TestMatchVModule guards against vmodule directory patterns (the
+// package's own documented example, "bar/*") being silently unmatchable
+// because the caller's file was reduced to its base name before matching.
+func TestMatchVModule(t *testing.T) {
+	cases := []struct {
+		pattern, file string
+		want          bool
+	}{
+		{"foo.go", "/home/x/project/foo.go", true},
+		{"foo.go", "/home/x/project/bar/foo.go", true},
+		{"bar/*", "/home/x/project/bar/baz.go", true},
+		{"bar/*", "/home/x/project/other/baz.go", false},
+		{"bar/*.go", "/home/x/project/bar/baz.go", true},
+		{"bar/*.go", "/home/x/project/bar/baz.txt", false},
+	}
+	for _, c := range cases {
+		if got := matchVModule(c.pattern, c.file); got != c.want {
+			t.Errorf("matchVModule(%q, %q) = %v, want %v", c.pattern, c.file, got, c.want)
+		}
+	}
+}